@@ -2,6 +2,7 @@ package tracking
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/milbertk/databasesmng"
 )
@@ -43,3 +44,55 @@ VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12);
 	fmt.Println("✅ Tracking inserted succesfully")
 	return nil
 }
+
+// InsertBatch inserts several LoginTracking rows in one round trip using a
+// single multi-row INSERT, for callers (e.g. AsyncSink) flushing a batch.
+func InsertBatch(records []*LoginTracking) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	db, err := databasesmng.CreateConnection()
+	if err != nil {
+		return fmt.Errorf("❌ DB connection error: %v", err)
+	}
+
+	const cols = 12
+	placeholders := make([]string, 0, len(records))
+	args := make([]interface{}, 0, len(records)*cols)
+	for i, lt := range records {
+		base := i * cols
+		ph := make([]string, cols)
+		for j := 0; j < cols; j++ {
+			ph[j] = fmt.Sprintf("$%d", base+j+1)
+		}
+		placeholders = append(placeholders, "("+strings.Join(ph, ", ")+")")
+		args = append(args,
+			lt.UserID,
+			lt.Email,
+			lt.DateLocalAcces,
+			lt.IP,
+			lt.Platform,
+			lt.MacAddress,
+			lt.Browser,
+			lt.CountryCode,
+			lt.GMTTime,
+			lt.Lang,
+			lt.Action,
+			lt.Jsonstring,
+		)
+	}
+
+	query := `
+		INSERT INTO public.logintracking (
+	userid, email, datelocalacces, ip, platform,
+	macaddress, browser, countrycode, gmttime, lang, action, jsonstring
+) VALUES ` + strings.Join(placeholders, ", ") + `;`
+
+	if _, err := db.Exec(query, args...); err != nil {
+		return fmt.Errorf("❌ Failed to insert batch of %d users: %v", len(records), err)
+	}
+
+	fmt.Printf("✅ Tracking batch of %d inserted succesfully\n", len(records))
+	return nil
+}
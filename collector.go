@@ -2,31 +2,123 @@ package tracking
 
 import (
 	"encoding/json"
+	"fmt"
 	"net"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/oschwald/geoip2-golang"
 )
 
+// reverseDNSTimeout bounds how long Extract/LookupIP will wait on net.LookupAddr
+// before giving up on a hostname for the client IP.
+const reverseDNSTimeout = 300 * time.Millisecond
+
 type Collector struct {
-	geo *geoip2.Reader // nil-safe: if nil, country will be "Unknown" unless a CDN header is present
+	mu         sync.RWMutex   // guards geo/city/asn when fed by a DBManager
+	geo        *geoip2.Reader // Country DB, nil-safe: country falls back to a CDN header or "Unknown"
+	city       *geoip2.Reader // City DB, nil-safe: city/region/lat-lon fields are left empty
+	asn        *geoip2.Reader // ASN DB, nil-safe: ASN/ASOrg fields are left empty
+	dnsCache   *rdnsCache
+	ipResolver *IPResolver
 }
 
+// NewCollector opens only a Country database, preserving the original
+// constructor for callers that don't need city/ASN/reverse-DNS data.
 func NewCollector(geoLite2Path string) (*Collector, error) {
 	db, err := geoip2.Open(geoLite2Path) // e.g., ./GeoLite2-Country.mmdb
 	if err != nil {
 		return nil, err
 	}
-	return &Collector{geo: db}, nil
+	return &Collector{geo: db, dnsCache: newRDNSCache(), ipResolver: NewIPResolver()}, nil
+}
+
+// NewCollectorWithDBs opens City, ASN, and Country databases together. Any
+// path may be empty, in which case the corresponding fields on Info stay
+// empty instead of erroring.
+func NewCollectorWithDBs(cityPath, asnPath, countryPath string) (*Collector, error) {
+	c := &Collector{dnsCache: newRDNSCache(), ipResolver: NewIPResolver()}
+
+	if cityPath != "" {
+		db, err := geoip2.Open(cityPath)
+		if err != nil {
+			return nil, err
+		}
+		c.city = db
+	}
+	if asnPath != "" {
+		db, err := geoip2.Open(asnPath)
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+		c.asn = db
+	}
+	if countryPath != "" {
+		db, err := geoip2.Open(countryPath)
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+		c.geo = db
+	}
+	return c, nil
+}
+
+// NewCollectorFromManager builds a Collector whose City/ASN/Country readers
+// are hot-swapped under c.mu whenever m reports a newly installed edition,
+// so a long-running server never needs a restart to pick up fresh data.
+func NewCollectorFromManager(m *DBManager) *Collector {
+	c := &Collector{dnsCache: newRDNSCache(), ipResolver: NewIPResolver()}
+	m.OnUpdate(func(edition, path string) {
+		db, err := geoip2.Open(path)
+		if err != nil {
+			fmt.Println("⚠️ failed to open refreshed", edition, "db:", err)
+			return
+		}
+		c.swap(edition, db)
+	})
+	return c
+}
+
+// swap installs db as the reader for edition, closing whatever reader it
+// replaces. Unknown editions are ignored.
+func (c *Collector) swap(edition string, db *geoip2.Reader) {
+	c.mu.Lock()
+	var old *geoip2.Reader
+	switch edition {
+	case "GeoLite2-City":
+		old, c.city = c.city, db
+	case "GeoLite2-ASN":
+		old, c.asn = c.asn, db
+	case "GeoLite2-Country":
+		old, c.geo = c.geo, db
+	default:
+		c.mu.Unlock()
+		db.Close()
+		return
+	}
+	c.mu.Unlock()
+	if old != nil {
+		old.Close()
+	}
 }
 
 func (c *Collector) Close() error {
-	if c.geo != nil {
-		return c.geo.Close()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var firstErr error
+	for _, db := range []*geoip2.Reader{c.geo, c.city, c.asn} {
+		if db == nil {
+			continue
+		}
+		if err := db.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
 	}
-	return nil
+	return firstErr
 }
 
 type Info struct {
@@ -38,26 +130,74 @@ type Info struct {
 	Lang        string `json:"lang"`         // first of Accept-Language, e.g., "es-CR"
 	UserAgent   string `json:"user_agent"`   // full UA for debugging
 	RequestTime string `json:"request_time"` // server time (for reference)
+
+	// City+ASN fields below are only populated when the matching mmdb was
+	// loaded; otherwise they are left at their zero value.
+	City       string  `json:"city,omitempty"`
+	Region     string  `json:"region,omitempty"`      // e.g., "California"
+	RegionCode string  `json:"region_code,omitempty"` // e.g., "CA"
+	Latitude   float64 `json:"latitude,omitempty"`
+	Longitude  float64 `json:"longitude,omitempty"`
+	TimeZone   string  `json:"time_zone,omitempty"`
+	ASN        uint    `json:"asn,omitempty"`
+	ASOrg      string  `json:"as_org,omitempty"`
+	Hostname   string  `json:"hostname,omitempty"` // reverse DNS of IP, best-effort
+
+	// UA fields below come from the pluggable UAParser (see uaparser.go).
+	// Browser/Platform above stay populated for backward compatibility with
+	// existing DB rows and downstream consumers.
+	UserAgentRaw   string `json:"user_agent_raw"`
+	BrowserVersion string `json:"browser_version,omitempty"`
+	OSVersion      string `json:"os_version,omitempty"`
+	DeviceType     string `json:"device_type,omitempty"` // "desktop" | "mobile" | "tablet" | "bot"
+	DeviceModel    string `json:"device_model,omitempty"`
+	IsBot          bool   `json:"is_bot,omitempty"`
+}
+
+// SetTrustedProxies configures the CIDRs Extract treats as trusted
+// intermediaries when walking X-Forwarded-For. See IPResolver for details.
+func (c *Collector) SetTrustedProxies(cidrs []string) error {
+	return c.ipResolver.SetTrustedProxies(cidrs)
+}
+
+// SetStrictIPResolution makes Extract's IP resolution fail closed: with no
+// usable header, the client IP is left empty instead of falling back to
+// RemoteAddr.
+func (c *Collector) SetStrictIPResolution(strict bool) {
+	c.ipResolver.SetStrict(strict)
 }
 
 // Extract gathers client metadata from *http.Request.
 // Country resolution order:
-//  1. If CDN sets CF-IPCountry, use that.
+//  1. If a CDN country header is present (see countryHeaders) and the peer
+//     is trusted by the IPResolver, use that.
 //  2. Else if GeoIP DB loaded, map IP -> ISO country code.
 //  3. Else "Unknown".
 func (c *Collector) Extract(r *http.Request) Info {
 	ua := r.Header.Get("User-Agent")
-	browser, platform := parseUA(ua)
-	ip := clientIP(r)
+	uaInfo := defaultUAParser.Parse(ua)
+	ip, _ := c.ipResolver.Resolve(r) // nil-safe: "" just skips GeoIP below
 	lang := firstLang(r.Header.Get("Accept-Language"))
 
-	// 1) Prefer CDN header if present (e.g., Cloudflare)
-	country := strings.TrimSpace(r.Header.Get("CF-IPCountry"))
+	// 1) Prefer a CDN-set country header if present, but only from a peer
+	// we'd also trust to set a client-IP header (see IPResolver).
+	country := ""
+	if c.ipResolver.peerTrusted(r) {
+		for _, h := range countryHeaders {
+			if v := strings.TrimSpace(r.Header.Get(h)); v != "" {
+				country = v
+				break
+			}
+		}
+	}
 
 	// 2) GeoIP fallback
-	if country == "" && c.geo != nil && ip != "" {
+	c.mu.RLock()
+	geo := c.geo
+	c.mu.RUnlock()
+	if country == "" && geo != nil && ip != "" {
 		if p := net.ParseIP(ip); p != nil {
-			if rec, err := c.geo.Country(p); err == nil && rec != nil && rec.Country.IsoCode != "" {
+			if rec, err := geo.Country(p); err == nil && rec != nil && rec.Country.IsoCode != "" {
 				country = rec.Country.IsoCode
 			}
 		}
@@ -70,80 +210,175 @@ func (c *Collector) Extract(r *http.Request) Info {
 	//   X-Client-UTC-Offset: String(-new Date().getTimezoneOffset())
 	gmt := strings.TrimSpace(r.Header.Get("X-Client-UTC-Offset"))
 
-	return Info{
-		IP:          ip,
-		Platform:    platform,
-		Browser:     browser,
-		CountryCode: country,
-		GMTTime:     gmt,
-		Lang:        lang,
-		UserAgent:   ua,
-		RequestTime: time.Now().Format("2006-01-02 15:04:05"),
+	info := Info{
+		IP:             ip,
+		Platform:       uaInfo.Platform,
+		Browser:        uaInfo.Browser,
+		CountryCode:    country,
+		GMTTime:        gmt,
+		Lang:           lang,
+		UserAgent:      ua,
+		UserAgentRaw:   ua,
+		RequestTime:    time.Now().Format("2006-01-02 15:04:05"),
+		BrowserVersion: uaInfo.BrowserVersion,
+		OSVersion:      uaInfo.OSVersion,
+		DeviceType:     uaInfo.DeviceType,
+		DeviceModel:    uaInfo.DeviceModel,
+		IsBot:          uaInfo.IsBot,
 	}
+	c.enrich(&info, ip)
+	return info
 }
 
-// JSON pretty-prints Info.
-func (i Info) JSON() string {
-	b, _ := json.MarshalIndent(i, "", "  ")
-	return string(b)
+// LookupIP enriches an arbitrary IP literal or hostname the same way Extract
+// does for a request's client IP, without requiring an *http.Request. A
+// hostname is resolved via net.LookupHost before GeoIP/ASN lookups run.
+func (c *Collector) LookupIP(ipOrHost string) (Info, error) {
+	ip := strings.TrimSpace(ipOrHost)
+	if net.ParseIP(ip) == nil {
+		addrs, err := net.LookupHost(ip)
+		if err != nil {
+			return Info{}, err
+		}
+		if len(addrs) == 0 {
+			return Info{}, &net.DNSError{Err: "no addresses found", Name: ipOrHost}
+		}
+		ip = addrs[0]
+	}
+
+	info := Info{IP: ip, RequestTime: time.Now().Format("2006-01-02 15:04:05")}
+	c.mu.RLock()
+	geo := c.geo
+	c.mu.RUnlock()
+	if geo != nil {
+		if p := net.ParseIP(ip); p != nil {
+			if rec, err := geo.Country(p); err == nil && rec != nil && rec.Country.IsoCode != "" {
+				info.CountryCode = rec.Country.IsoCode
+			}
+		}
+	}
+	if info.CountryCode == "" {
+		info.CountryCode = "Unknown"
+	}
+	c.enrich(&info, ip)
+	return info, nil
 }
 
-// ----------------- helpers -----------------
+// enrich fills in City/ASN/reverse-DNS fields on info, nil-safe for any
+// subset of databases actually loaded on c.
+func (c *Collector) enrich(info *Info, ip string) {
+	if ip == "" {
+		return
+	}
+	p := net.ParseIP(ip)
+	if p == nil {
+		return
+	}
+
+	c.mu.RLock()
+	city, asn := c.city, c.asn
+	c.mu.RUnlock()
 
-func clientIP(r *http.Request) string {
-	// X-Forwarded-For may have multiple IPs: client, proxy1, proxy2...
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		for _, p := range strings.Split(xff, ",") {
-			p = strings.TrimSpace(p)
-			if net.ParseIP(p) != nil {
-				return p
+	if city != nil {
+		if rec, err := city.City(p); err == nil && rec != nil {
+			info.City = rec.City.Names["en"]
+			info.TimeZone = rec.Location.TimeZone
+			info.Latitude = rec.Location.Latitude
+			info.Longitude = rec.Location.Longitude
+			if len(rec.Subdivisions) > 0 {
+				info.Region = rec.Subdivisions[0].Names["en"]
+				info.RegionCode = rec.Subdivisions[0].IsoCode
 			}
 		}
 	}
-	// Fallback to RemoteAddr (host:port)
-	host, _, err := net.SplitHostPort(strings.TrimSpace(r.RemoteAddr))
-	if err == nil && net.ParseIP(host) != nil {
-		return host
-	}
-	if ip := net.ParseIP(r.RemoteAddr); ip != nil {
-		return ip.String()
-	}
-	return ""
-}
-
-func parseUA(ua string) (browser, platform string) {
-	u := strings.ToLower(ua)
-	// browser
-	switch {
-	case strings.Contains(u, "edg/"):
-		browser = "Edge"
-	case strings.Contains(u, "chrome/"):
-		browser = "Chrome"
-	case strings.Contains(u, "firefox/"):
-		browser = "Firefox"
-	case strings.Contains(u, "safari/"):
-		browser = "Safari"
-	default:
-		browser = "Unknown"
-	}
-	// platform
-	switch {
-	case strings.Contains(u, "windows"):
-		platform = "Windows"
-	case strings.Contains(u, "macintosh") || strings.Contains(u, "mac os"):
-		platform = "macOS"
-	case strings.Contains(u, "android"):
-		platform = "Android"
-	case strings.Contains(u, "iphone") || strings.Contains(u, "ipad") || strings.Contains(u, "ios"):
-		platform = "iOS"
-	case strings.Contains(u, "linux"):
-		platform = "Linux"
-	default:
-		platform = "Unknown"
+
+	if asn != nil {
+		if rec, err := asn.ASN(p); err == nil && rec != nil {
+			info.ASN = rec.AutonomousSystemNumber
+			info.ASOrg = rec.AutonomousSystemOrganization
+		}
 	}
-	return
+
+	info.Hostname = c.dnsCache.lookup(ip)
+}
+
+// rdnsCacheTTL bounds how long a cached reverse-DNS result (including a
+// negative one from a timed-out lookup) is reused before being retried.
+const rdnsCacheTTL = 10 * time.Minute
+
+// rdnsCacheMaxEntries bounds the cache's memory footprint for a Collector
+// whose LookupIP is used against an open-ended set of IPs, not just a
+// request's client IP. Oldest entries are evicted first (FIFO) once full.
+const rdnsCacheMaxEntries = 10000
+
+type rdnsEntry struct {
+	host      string
+	expiresAt time.Time
 }
 
+// rdnsCache memoizes net.LookupAddr results behind reverseDNSTimeout so
+// Extract never blocks a request for longer than that, even on a DNS
+// resolver that hangs. Entries expire after rdnsCacheTTL and the cache is
+// capped at rdnsCacheMaxEntries to keep long-running servers bounded.
+type rdnsCache struct {
+	mu    sync.Mutex
+	cache map[string]rdnsEntry
+	order []string // insertion order, for FIFO eviction
+}
+
+func newRDNSCache() *rdnsCache {
+	return &rdnsCache{cache: make(map[string]rdnsEntry)}
+}
+
+func (c *rdnsCache) lookup(ip string) string {
+	c.mu.Lock()
+	if e, ok := c.cache[ip]; ok && time.Now().Before(e.expiresAt) {
+		c.mu.Unlock()
+		return e.host
+	}
+	c.mu.Unlock()
+
+	type result struct{ host string }
+	ch := make(chan result, 1)
+	go func() {
+		names, err := net.LookupAddr(ip)
+		host := ""
+		if err == nil && len(names) > 0 {
+			host = strings.TrimSuffix(names[0], ".")
+		}
+		ch <- result{host: host}
+	}()
+
+	var host string
+	select {
+	case res := <-ch:
+		host = res.host
+	case <-time.After(reverseDNSTimeout):
+		host = ""
+	}
+
+	c.mu.Lock()
+	if _, exists := c.cache[ip]; !exists {
+		if len(c.order) >= rdnsCacheMaxEntries {
+			evict := c.order[0]
+			c.order = c.order[1:]
+			delete(c.cache, evict)
+		}
+		c.order = append(c.order, ip)
+	}
+	c.cache[ip] = rdnsEntry{host: host, expiresAt: time.Now().Add(rdnsCacheTTL)}
+	c.mu.Unlock()
+	return host
+}
+
+// JSON pretty-prints Info.
+func (i Info) JSON() string {
+	b, _ := json.MarshalIndent(i, "", "  ")
+	return string(b)
+}
+
+// ----------------- helpers -----------------
+
 func firstLang(al string) string {
 	al = strings.TrimSpace(al)
 	if al == "" {
@@ -0,0 +1,271 @@
+package tracking
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultRefreshInterval is how often DBManager re-checks for new editions
+// unless SetRefreshInterval overrides it.
+const defaultRefreshInterval = 7 * 24 * time.Hour
+
+const maxMindDownloadURL = "https://download.maxmind.com/app/geoip_download?edition_id=%s&license_key=%s&suffix=tar.gz"
+
+// DBManager downloads and keeps MaxMind GeoLite2 .mmdb files up to date on
+// disk, verifying the published SHA256 checksum before installing each one.
+// It is safe for concurrent use.
+type DBManager struct {
+	licenseKey string
+	destDir    string
+	editions   []string
+	interval   time.Duration
+	urlFor     func(edition string) string // overridable for mirrors/tests
+
+	mu    sync.RWMutex
+	paths map[string]string // edition -> path of installed .mmdb
+
+	onUpdate func(edition, path string)
+}
+
+// NewDBManager creates a manager that downloads the given GeoLite2 editions
+// (e.g. "GeoLite2-City", "GeoLite2-ASN", "GeoLite2-Country") from MaxMind
+// using licenseKey, storing the extracted .mmdb files under destDir.
+func NewDBManager(licenseKey, destDir string, editions []string) *DBManager {
+	m := &DBManager{
+		licenseKey: licenseKey,
+		destDir:    destDir,
+		editions:   editions,
+		interval:   defaultRefreshInterval,
+		paths:      make(map[string]string),
+	}
+	m.urlFor = func(edition string) string {
+		return fmt.Sprintf(maxMindDownloadURL, edition, m.licenseKey)
+	}
+	return m
+}
+
+// SetRefreshInterval overrides the default weekly refresh cadence used by Start.
+func (m *DBManager) SetRefreshInterval(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.interval = d
+}
+
+// SetEditionURL overrides the download URL for a single edition, e.g. to
+// point at a mirror or a "file://" path for air-gapped deployments.
+func (m *DBManager) SetEditionURL(edition, rawURL string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	prev := m.urlFor
+	m.urlFor = func(e string) string {
+		if e == edition {
+			return rawURL
+		}
+		return prev(e)
+	}
+}
+
+// OnUpdate registers a callback invoked whenever an edition is (re)installed,
+// with the edition name and the new .mmdb path. Collector uses this to hot-swap.
+func (m *DBManager) OnUpdate(fn func(edition, path string)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onUpdate = fn
+}
+
+// Path returns the currently installed .mmdb path for edition, or "" if it
+// has not been downloaded yet.
+func (m *DBManager) Path(edition string) string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.paths[edition]
+}
+
+// RefreshAll downloads and installs every configured edition once.
+func (m *DBManager) RefreshAll(ctx context.Context) error {
+	for _, edition := range m.editions {
+		if err := m.refreshOne(ctx, edition); err != nil {
+			return fmt.Errorf("refresh %s: %w", edition, err)
+		}
+	}
+	return nil
+}
+
+// Start runs RefreshAll once, then continues refreshing on the configured
+// interval until ctx is cancelled.
+func (m *DBManager) Start(ctx context.Context) error {
+	if err := m.RefreshAll(ctx); err != nil {
+		return err
+	}
+
+	m.mu.RLock()
+	interval := m.interval
+	m.mu.RUnlock()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := m.RefreshAll(ctx); err != nil {
+				fmt.Println("⚠️ GeoIP refresh failed:", err)
+			}
+		}
+	}
+}
+
+func (m *DBManager) refreshOne(ctx context.Context, edition string) error {
+	m.mu.RLock()
+	archiveURL := m.urlFor(edition)
+	m.mu.RUnlock()
+
+	tarGz, err := fetch(ctx, archiveURL)
+	if err != nil {
+		return err
+	}
+	defer tarGz.Close()
+	archive, err := io.ReadAll(tarGz)
+	if err != nil {
+		return err
+	}
+
+	checksumURL := archiveURL + ".sha256"
+	wantSum, err := fetchChecksum(ctx, checksumURL)
+	if err != nil {
+		return fmt.Errorf("fetch checksum: %w", err)
+	}
+
+	// The published checksum covers the .tar.gz archive itself.
+	archiveSum := sha256.Sum256(archive)
+	gotSum := hex.EncodeToString(archiveSum[:])
+	if wantSum != "" && !strings.EqualFold(wantSum, gotSum) {
+		return fmt.Errorf("checksum mismatch for %s: want %s got %s", edition, wantSum, gotSum)
+	}
+
+	mmdbBytes, _, err := extractMMDB(bytes.NewReader(archive), edition)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(m.destDir, 0o755); err != nil {
+		return err
+	}
+	finalPath := filepath.Join(m.destDir, edition+".mmdb")
+	tmp, err := os.CreateTemp(m.destDir, edition+".*.mmdb.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	if _, err := tmp.Write(mmdbBytes); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, finalPath); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	m.mu.Lock()
+	m.paths[edition] = finalPath
+	onUpdate := m.onUpdate
+	m.mu.Unlock()
+
+	if onUpdate != nil {
+		onUpdate(edition, finalPath)
+	}
+	return nil
+}
+
+// fetch opens rawURL, supporting both http(s):// and file:// schemes so
+// air-gapped deployments can point at a local mirror.
+func fetch(ctx context.Context, rawURL string) (io.ReadCloser, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "file" {
+		return os.Open(u.Path)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("download %s: status %s", rawURL, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+func fetchChecksum(ctx context.Context, rawURL string) (string, error) {
+	rc, err := fetch(ctx, rawURL)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	b, err := io.ReadAll(rc)
+	if err != nil {
+		return "", err
+	}
+	// MaxMind's .sha256 files are "<hex>  <filename>"
+	fields := strings.Fields(string(b))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("empty checksum response from %s", rawURL)
+	}
+	return strings.TrimSpace(fields[0]), nil
+}
+
+// extractMMDB untars a gzip'd MaxMind archive and returns the bytes of the
+// single .mmdb file inside along with its SHA256 hex digest.
+func extractMMDB(gz io.Reader, edition string) ([]byte, string, error) {
+	zr, err := gzip.NewReader(gz)
+	if err != nil {
+		return nil, "", err
+	}
+	defer zr.Close()
+
+	tr := tar.NewReader(zr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, "", fmt.Errorf("no .mmdb file found in archive for %s", edition)
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		if !strings.HasSuffix(hdr.Name, ".mmdb") {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, "", err
+		}
+		sum := sha256.Sum256(data)
+		return data, hex.EncodeToString(sum[:]), nil
+	}
+}
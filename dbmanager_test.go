@@ -0,0 +1,124 @@
+package tracking
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// buildTestArchive returns a gzip'd tar containing a single edition.mmdb
+// entry with the given content, mimicking a MaxMind GeoLite2 tarball.
+func buildTestArchive(t *testing.T, edition string, content []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	hdr := &tar.Header{
+		Name: edition + "_20240101/" + edition + ".mmdb",
+		Mode: 0o644,
+		Size: int64(len(content)),
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		t.Fatalf("write tar header: %v", err)
+	}
+	if _, err := tw.Write(content); err != nil {
+		t.Fatalf("write tar content: %v", err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("close tar writer: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("close gzip writer: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestDBManagerRefreshOne_ChecksumMatchInstalls(t *testing.T) {
+	tmp := t.TempDir()
+	destDir := filepath.Join(tmp, "dest")
+
+	edition := "GeoLite2-Test"
+	content := []byte("fake-mmdb-bytes")
+	archive := buildTestArchive(t, edition, content)
+	sum := sha256.Sum256(archive)
+
+	archivePath := filepath.Join(tmp, edition+".tar.gz")
+	if err := os.WriteFile(archivePath, archive, 0o644); err != nil {
+		t.Fatalf("write archive: %v", err)
+	}
+	if err := os.WriteFile(archivePath+".sha256", []byte(hex.EncodeToString(sum[:])+"  "+edition+".tar.gz\n"), 0o644); err != nil {
+		t.Fatalf("write checksum: %v", err)
+	}
+
+	m := NewDBManager("unused-license-key", destDir, []string{edition})
+	m.SetEditionURL(edition, "file://"+archivePath)
+
+	if err := m.RefreshAll(context.Background()); err != nil {
+		t.Fatalf("RefreshAll: %v", err)
+	}
+
+	installed := m.Path(edition)
+	if installed == "" {
+		t.Fatal("expected Path to return the installed .mmdb path")
+	}
+	got, err := os.ReadFile(installed)
+	if err != nil {
+		t.Fatalf("read installed mmdb: %v", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("installed mmdb content = %q, want %q", got, content)
+	}
+}
+
+func TestDBManagerRefreshOne_ChecksumMismatchRejected(t *testing.T) {
+	tmp := t.TempDir()
+	destDir := filepath.Join(tmp, "dest")
+
+	edition := "GeoLite2-Test"
+	content := []byte("fake-mmdb-bytes")
+	archive := buildTestArchive(t, edition, content)
+
+	archivePath := filepath.Join(tmp, edition+".tar.gz")
+	if err := os.WriteFile(archivePath, archive, 0o644); err != nil {
+		t.Fatalf("write archive: %v", err)
+	}
+	wrongSum := sha256.Sum256([]byte("not-the-real-content"))
+	if err := os.WriteFile(archivePath+".sha256", []byte(hex.EncodeToString(wrongSum[:])+"  "+edition+".tar.gz\n"), 0o644); err != nil {
+		t.Fatalf("write checksum: %v", err)
+	}
+
+	m := NewDBManager("unused-license-key", destDir, []string{edition})
+	m.SetEditionURL(edition, "file://"+archivePath)
+
+	if err := m.RefreshAll(context.Background()); err == nil {
+		t.Fatal("expected RefreshAll to fail on checksum mismatch")
+	}
+	if m.Path(edition) != "" {
+		t.Error("no edition should be installed after a checksum mismatch")
+	}
+	if _, err := os.Stat(destDir); err == nil {
+		entries, _ := os.ReadDir(destDir)
+		if len(entries) != 0 {
+			t.Errorf("destDir should be empty after a checksum mismatch, found %d entries", len(entries))
+		}
+	}
+}
+
+func TestFetchChecksum_EmptyResponseErrors(t *testing.T) {
+	tmp := t.TempDir()
+	path := filepath.Join(tmp, "empty.sha256")
+	if err := os.WriteFile(path, []byte("   \n"), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	if _, err := fetchChecksum(context.Background(), "file://"+path); err == nil {
+		t.Fatal("expected an error for an empty checksum response, got nil")
+	}
+}
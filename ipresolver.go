@@ -0,0 +1,206 @@
+package tracking
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ErrNoClientIP is returned by IPResolver.Resolve in strict mode when no
+// header yields a usable client IP; RemoteAddr is not consulted then.
+var ErrNoClientIP = errors.New("tracking: no client IP found")
+
+// singleValueIPHeaders are consulted, in order, before X-Forwarded-For and
+// Forwarded, since a CDN setting one of these is normally authoritative for
+// the real client.
+var singleValueIPHeaders = []string{
+	"CF-Connecting-IP",
+	"True-Client-IP",
+	"Fastly-Client-IP",
+	"X-Real-IP",
+}
+
+// countryHeaders lists the CDN-set country headers Extract checks, in
+// priority order, before falling back to GeoIP.
+var countryHeaders = []string{
+	"CF-IPCountry",
+	"X-Vercel-IP-Country",
+	"Fastly-Geo-Country-Code",
+	"X-AppEngine-Country",
+}
+
+// IPResolver determines the real client IP for an incoming request, given a
+// configurable set of trusted proxy CIDRs. With no trusted proxies
+// configured every peer's headers are trusted: the single-value headers and
+// Forwarded are consulted first, then the first parseable X-Forwarded-For
+// entry, then RemoteAddr.
+type IPResolver struct {
+	trustedProxies []*net.IPNet
+	strict         bool // error instead of silently falling back to RemoteAddr
+	requirePublic  bool // reject private/loopback/ULA candidates
+}
+
+// NewIPResolver returns a resolver with no trusted proxies configured.
+func NewIPResolver() *IPResolver {
+	return &IPResolver{}
+}
+
+// SetTrustedProxies replaces the trusted-proxy CIDR list used when walking
+// X-Forwarded-For. An invalid CIDR is reported immediately and leaves the
+// previous list untouched.
+func (r *IPResolver) SetTrustedProxies(cidrs []string) error {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, c := range cidrs {
+		_, ipnet, err := net.ParseCIDR(c)
+		if err != nil {
+			return err
+		}
+		nets = append(nets, ipnet)
+	}
+	r.trustedProxies = nets
+	return nil
+}
+
+// SetStrict controls whether Resolve returns ErrNoClientIP (true) or falls
+// back to RemoteAddr / "" (false, the default) when no header yields an IP.
+func (r *IPResolver) SetStrict(strict bool) {
+	r.strict = strict
+}
+
+// SetRequirePublic rejects private/loopback/link-local/ULA candidate IPs,
+// continuing to the next header/RemoteAddr instead of returning one of them.
+func (r *IPResolver) SetRequirePublic(require bool) {
+	r.requirePublic = require
+}
+
+func (r *IPResolver) isTrustedProxy(ip net.IP) bool {
+	for _, n := range r.trustedProxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// peerTrusted reports whether req's immediate peer (RemoteAddr) should be
+// trusted to set client-IP headers. With no trust policy configured, every
+// peer is trusted, preserving the original header-trusting behavior.
+func (r *IPResolver) peerTrusted(req *http.Request) bool {
+	if len(r.trustedProxies) == 0 {
+		return true
+	}
+	host, _, err := net.SplitHostPort(strings.TrimSpace(req.RemoteAddr))
+	if err != nil {
+		host = strings.TrimSpace(req.RemoteAddr)
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && r.isTrustedProxy(ip)
+}
+
+func (r *IPResolver) acceptable(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	if !r.requirePublic {
+		return true
+	}
+	return !(ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast())
+}
+
+// Resolve returns the best-guess real client IP for req. Client-supplied
+// headers (the single-value ones, Forwarded, and X-Forwarded-For) are
+// trusted when no trust policy is configured, or when req's immediate peer
+// is in it; otherwise only RemoteAddr is used.
+func (r *IPResolver) Resolve(req *http.Request) (string, error) {
+	trustHeaders := r.peerTrusted(req)
+
+	if trustHeaders {
+		for _, h := range singleValueIPHeaders {
+			if v := strings.TrimSpace(req.Header.Get(h)); v != "" {
+				if ip := net.ParseIP(v); ip != nil && r.acceptable(ip) {
+					return ip.String(), nil
+				}
+			}
+		}
+
+		if fwd := req.Header.Get("Forwarded"); fwd != "" {
+			if ip := parseForwarded(fwd); ip != nil && r.acceptable(ip) {
+				return ip.String(), nil
+			}
+		}
+	}
+
+	if xff := req.Header.Get("X-Forwarded-For"); trustHeaders && xff != "" {
+		parts := strings.Split(xff, ",")
+		if len(r.trustedProxies) > 0 {
+			// Walk right-to-left (most recently appended first), skipping
+			// trusted proxies, so a spoofed leading entry can't win.
+			for i := len(parts) - 1; i >= 0; i-- {
+				ip := net.ParseIP(strings.TrimSpace(parts[i]))
+				if ip == nil || r.isTrustedProxy(ip) {
+					continue
+				}
+				if r.acceptable(ip) {
+					return ip.String(), nil
+				}
+			}
+		} else {
+			// No trust policy configured: preserve the original behavior of
+			// trusting the first parseable entry.
+			for _, p := range parts {
+				ip := net.ParseIP(strings.TrimSpace(p))
+				if ip != nil && r.acceptable(ip) {
+					return ip.String(), nil
+				}
+			}
+		}
+	}
+
+	if r.strict {
+		return "", ErrNoClientIP
+	}
+
+	host, _, err := net.SplitHostPort(strings.TrimSpace(req.RemoteAddr))
+	if err == nil {
+		if ip := net.ParseIP(host); ip != nil {
+			return ip.String(), nil
+		}
+	}
+	if ip := net.ParseIP(req.RemoteAddr); ip != nil {
+		return ip.String(), nil
+	}
+	return "", nil
+}
+
+// parseForwarded extracts the first for= token from an RFC 7239 Forwarded
+// header, handling quoted values and bracketed IPv6 literals, e.g.:
+//
+//	Forwarded: for=192.0.2.60;proto=http;by=203.0.113.43
+//	Forwarded: for="[2001:db8:cafe::17]:4711"
+func parseForwarded(header string) net.IP {
+	// Forwarded may list multiple hops separated by commas; the first one
+	// is the closest to the original client.
+	firstHop := header
+	if i := strings.IndexByte(header, ','); i > 0 {
+		firstHop = header[:i]
+	}
+
+	for _, pair := range strings.Split(firstHop, ";") {
+		kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(kv) != 2 || !strings.EqualFold(strings.TrimSpace(kv[0]), "for") {
+			continue
+		}
+		val := strings.Trim(strings.TrimSpace(kv[1]), `"`)
+		val = strings.TrimPrefix(val, "[")
+		if i := strings.Index(val, "]"); i >= 0 {
+			val = val[:i]
+		} else if i := strings.LastIndex(val, ":"); i >= 0 && strings.Count(val, ":") == 1 {
+			// host:port for an IPv4 literal (an unbracketed IPv6 literal has
+			// several colons, so it is left whole and parsed as-is).
+			val = val[:i]
+		}
+		return net.ParseIP(val)
+	}
+	return nil
+}
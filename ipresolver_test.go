@@ -0,0 +1,109 @@
+package tracking
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newReq(remoteAddr string, headers map[string]string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = remoteAddr
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+	return r
+}
+
+func TestIPResolverResolve_NoTrustPolicy(t *testing.T) {
+	r := NewIPResolver()
+
+	req := newReq("203.0.113.99:1234", map[string]string{
+		"CF-Connecting-IP": "1.2.3.4",
+	})
+	got, err := r.Resolve(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "1.2.3.4" {
+		t.Errorf("got %q, want %q (headers trusted when no policy is configured)", got, "1.2.3.4")
+	}
+}
+
+func TestIPResolverResolve_UntrustedPeerHeadersIgnored(t *testing.T) {
+	r := NewIPResolver()
+	if err := r.SetTrustedProxies([]string{"10.0.0.0/8"}); err != nil {
+		t.Fatalf("SetTrustedProxies: %v", err)
+	}
+
+	req := newReq("203.0.113.99:1234", map[string]string{
+		"CF-Connecting-IP": "1.2.3.4",
+	})
+	got, err := r.Resolve(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "203.0.113.99" {
+		t.Errorf("got %q, want RemoteAddr %q (spoofed header from an untrusted peer must be ignored)", got, "203.0.113.99")
+	}
+}
+
+func TestIPResolverResolve_TrustedPeerHeadersHonored(t *testing.T) {
+	r := NewIPResolver()
+	if err := r.SetTrustedProxies([]string{"10.0.0.0/8"}); err != nil {
+		t.Fatalf("SetTrustedProxies: %v", err)
+	}
+
+	req := newReq("10.1.2.3:1234", map[string]string{
+		"CF-Connecting-IP": "1.2.3.4",
+	})
+	got, err := r.Resolve(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "1.2.3.4" {
+		t.Errorf("got %q, want %q (header from a trusted peer should be honored)", got, "1.2.3.4")
+	}
+}
+
+func TestIPResolverResolve_XFFWalksRightToLeftSkippingTrustedProxies(t *testing.T) {
+	r := NewIPResolver()
+	if err := r.SetTrustedProxies([]string{"10.0.0.0/8"}); err != nil {
+		t.Fatalf("SetTrustedProxies: %v", err)
+	}
+
+	req := newReq("10.0.0.1:1234", map[string]string{
+		"X-Forwarded-For": "9.9.9.9, 1.2.3.4, 10.0.0.5",
+	})
+	got, err := r.Resolve(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "1.2.3.4" {
+		t.Errorf("got %q, want %q (should skip trailing trusted proxy and stop at first untrusted hop)", got, "1.2.3.4")
+	}
+}
+
+func TestIPResolverResolve_StrictModeErrorsWithoutFallback(t *testing.T) {
+	r := NewIPResolver()
+	r.SetStrict(true)
+
+	req := newReq("not-an-ip", nil)
+	_, err := r.Resolve(req)
+	if err != ErrNoClientIP {
+		t.Errorf("got err %v, want ErrNoClientIP", err)
+	}
+}
+
+func TestIPResolverResolve_NonStrictFallsBackToRemoteAddr(t *testing.T) {
+	r := NewIPResolver()
+
+	req := newReq("198.51.100.7:5555", nil)
+	got, err := r.Resolve(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "198.51.100.7" {
+		t.Errorf("got %q, want %q", got, "198.51.100.7")
+	}
+}
@@ -0,0 +1,125 @@
+package tracking
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// contextKey namespaces the keys Middleware reads so they don't collide with
+// keys set by other packages sharing the same request context.
+type contextKey string
+
+const (
+	// UserIDContextKey, EmailContextKey, and ActionContextKey are the
+	// request context keys Middleware reads. Upstream auth middleware
+	// should set these (e.g. via WithUserID) before calling next.
+	UserIDContextKey contextKey = "tracking.user_id"
+	EmailContextKey  contextKey = "tracking.email"
+	ActionContextKey contextKey = "tracking.action"
+)
+
+// WithUserID, WithEmail, and WithAction attach the corresponding value to
+// ctx so a downstream Middleware call can pick it up.
+func WithUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, UserIDContextKey, userID)
+}
+
+func WithEmail(ctx context.Context, email string) context.Context {
+	return context.WithValue(ctx, EmailContextKey, email)
+}
+
+func WithAction(ctx context.Context, action string) context.Context {
+	return context.WithValue(ctx, ActionContextKey, action)
+}
+
+func stringFromContext(ctx context.Context, key contextKey) string {
+	v, _ := ctx.Value(key).(string)
+	return v
+}
+
+// Middleware wraps an http.Handler, extracting client metadata with
+// collector and persisting a LoginTracking record to sink on every request.
+// UserID/Email/Action are read from the request context, so an upstream
+// auth middleware should set them (via WithUserID etc.) before this one
+// runs. Extraction and the sink write happen in a background goroutine after
+// next.ServeHTTP returns, so a cold reverse-DNS lookup (or any other slow
+// Sink) never delays flushing the response: tracking must never fail or
+// delay the actual response.
+func Middleware(collector *Collector, sink Sink) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r)
+
+			ctx := r.Context()
+			go func() {
+				info := collector.Extract(r)
+				lt := buildRecord(ctx, info)
+				// The request's context may already be canceled by the time
+				// this runs (it's only used above for its stored values), so
+				// the sink write gets a fresh, uncancelled context.
+				if err := sink.Record(context.Background(), lt); err != nil {
+					fmt.Println("⚠️ tracking sink error:", err)
+				}
+			}()
+		})
+	}
+}
+
+// MiddlewareWithRules behaves like Middleware, but first evaluates info
+// against engine: a Deny verdict skips the insert entirely, matched tags are
+// merged into lt.Jsonstring under "rule_tags", and a Route verdict sends the
+// record to routes[sinkName] instead of the default sink (falling back to
+// the default sink if that name isn't registered). Like Middleware, this all
+// happens in a background goroutine so it never delays the response.
+func MiddlewareWithRules(collector *Collector, sink Sink, engine *RuleEngine, routes map[string]Sink) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r)
+
+			ctx := r.Context()
+			go func() {
+				info := collector.Extract(r)
+				lt := buildRecord(ctx, info)
+
+				decision := engine.Evaluate(info)
+				if !decision.Allowed {
+					return
+				}
+				if len(decision.Tags) > 0 {
+					lt.Jsonstring = mergeRuleTags(lt.Jsonstring, decision.Tags)
+				}
+
+				target := sink
+				if decision.RouteSink != "" {
+					if routed, ok := routes[decision.RouteSink]; ok {
+						target = routed
+					}
+				}
+
+				if err := target.Record(context.Background(), lt); err != nil {
+					fmt.Println("⚠️ tracking sink error:", err)
+				}
+			}()
+		})
+	}
+}
+
+// buildRecord assembles the LoginTracking row Middleware persists from the
+// collected Info and whatever upstream auth middleware stashed in ctx.
+func buildRecord(ctx context.Context, info Info) *LoginTracking {
+	extra, _ := json.Marshal(info)
+	return &LoginTracking{
+		UserID:      stringFromContext(ctx, UserIDContextKey),
+		Email:       stringFromContext(ctx, EmailContextKey),
+		IP:          info.IP,
+		Platform:    info.Platform,
+		Browser:     info.Browser,
+		CountryCode: info.CountryCode,
+		GMTTime:     info.GMTTime,
+		Lang:        info.Lang,
+		Action:      stringFromContext(ctx, ActionContextKey),
+		Jsonstring:  string(extra),
+	}
+}
@@ -0,0 +1,136 @@
+package tracking
+
+import (
+	"encoding/json"
+	"net"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Matcher identifies what field of a collected Info a Rule is matched
+// against.
+type Matcher string
+
+const (
+	MatcherIPCIDR    Matcher = "IP-CIDR"
+	MatcherIPCountry Matcher = "IP-COUNTRY"
+	MatcherIPASN     Matcher = "IP-ASN"
+	MatcherUABot     Matcher = "UA-BOT"
+)
+
+// ActionKind is the verdict a matching Rule applies.
+type ActionKind string
+
+const (
+	ActionAllow ActionKind = "allow"
+	ActionDeny  ActionKind = "deny"
+	ActionTag   ActionKind = "tag"
+	ActionRoute ActionKind = "route"
+)
+
+// Rule is one policy entry: if Matcher/Value matches the collected Info,
+// Action (and ActionValue, for Tag/Route) applies.
+type Rule struct {
+	Matcher     Matcher    `yaml:"matcher" json:"matcher"`
+	Value       string     `yaml:"value" json:"value"` // CIDR, ISO country code, or AS number; ignored for UA-BOT
+	Action      ActionKind `yaml:"action" json:"action"`
+	ActionValue string     `yaml:"action_value,omitempty" json:"action_value,omitempty"` // tag text or sink name
+}
+
+// Decision is the result of evaluating a Rule set against an Info.
+type Decision struct {
+	Allowed   bool
+	Tags      []string
+	RouteSink string
+}
+
+// RuleEngine evaluates Rules, in order, against collected client Info before
+// a caller inserts a LoginTracking record. The first matching rule wins.
+type RuleEngine struct {
+	rules []Rule
+}
+
+// NewRuleEngine builds a RuleEngine from a Go slice of rules.
+func NewRuleEngine(rules []Rule) *RuleEngine {
+	return &RuleEngine{rules: rules}
+}
+
+// LoadRuleEngineYAML parses a YAML document (a top-level list of rules) and
+// builds a RuleEngine from it.
+func LoadRuleEngineYAML(data []byte) (*RuleEngine, error) {
+	var rules []Rule
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return nil, err
+	}
+	return NewRuleEngine(rules), nil
+}
+
+// Evaluate returns the Decision for info: the action of the first rule whose
+// matcher matches, or Allowed=true if no rule matches.
+func (e *RuleEngine) Evaluate(info Info) Decision {
+	for _, rule := range e.rules {
+		if !ruleMatches(rule, info) {
+			continue
+		}
+		switch rule.Action {
+		case ActionDeny:
+			return Decision{Allowed: false}
+		case ActionTag:
+			return Decision{Allowed: true, Tags: []string{rule.ActionValue}}
+		case ActionRoute:
+			return Decision{Allowed: true, RouteSink: rule.ActionValue}
+		default: // ActionAllow, or an unrecognized action defaults to allow
+			return Decision{Allowed: true}
+		}
+	}
+	return Decision{Allowed: true}
+}
+
+func ruleMatches(rule Rule, info Info) bool {
+	switch rule.Matcher {
+	case MatcherIPCIDR:
+		_, ipnet, err := net.ParseCIDR(rule.Value)
+		if err != nil {
+			return false
+		}
+		ip := net.ParseIP(info.IP)
+		return ip != nil && ipnet.Contains(ip)
+
+	case MatcherIPCountry:
+		return info.CountryCode != "" && strings.EqualFold(info.CountryCode, rule.Value)
+
+	case MatcherIPASN:
+		asn, err := strconv.ParseUint(rule.Value, 10, 64)
+		return err == nil && info.ASN == uint(asn)
+
+	case MatcherUABot:
+		return info.IsBot
+
+	default:
+		return false
+	}
+}
+
+// mergeRuleTags merges tags into jsonstring's "rule_tags" array, preserving
+// whatever else is already in that JSON blob (normally Info, from
+// Middleware). Malformed input is replaced rather than left corrupted.
+func mergeRuleTags(jsonstring string, tags []string) string {
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(jsonstring), &doc); err != nil || doc == nil {
+		doc = map[string]interface{}{}
+	}
+
+	existing, _ := doc["rule_tags"].([]interface{})
+	for _, t := range tags {
+		existing = append(existing, t)
+	}
+	doc["rule_tags"] = existing
+
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return jsonstring
+	}
+	return string(b)
+}
@@ -0,0 +1,87 @@
+package tracking
+
+import "testing"
+
+func TestRuleEngineEvaluate_FirstMatchWins(t *testing.T) {
+	engine := NewRuleEngine([]Rule{
+		{Matcher: MatcherIPCIDR, Value: "10.0.0.0/8", Action: ActionTag, ActionValue: "internal"},
+		{Matcher: MatcherIPCIDR, Value: "10.0.0.0/8", Action: ActionDeny},
+	})
+
+	d := engine.Evaluate(Info{IP: "10.1.2.3"})
+	if !d.Allowed {
+		t.Fatal("expected the first matching rule (Tag) to win, not the second (Deny)")
+	}
+	if len(d.Tags) != 1 || d.Tags[0] != "internal" {
+		t.Errorf("got Tags=%v, want [internal]", d.Tags)
+	}
+}
+
+func TestRuleEngineEvaluate_Deny(t *testing.T) {
+	engine := NewRuleEngine([]Rule{
+		{Matcher: MatcherUABot, Action: ActionDeny},
+	})
+
+	d := engine.Evaluate(Info{IsBot: true})
+	if d.Allowed {
+		t.Error("expected bot traffic to be denied")
+	}
+}
+
+func TestRuleEngineEvaluate_CountryRoute(t *testing.T) {
+	engine := NewRuleEngine([]Rule{
+		{Matcher: MatcherIPCountry, Value: "FR", Action: ActionRoute, ActionValue: "gdpr_sink"},
+	})
+
+	d := engine.Evaluate(Info{CountryCode: "fr"})
+	if !d.Allowed {
+		t.Fatal("Route should still allow the insert")
+	}
+	if d.RouteSink != "gdpr_sink" {
+		t.Errorf("got RouteSink=%q, want %q (country match should be case-insensitive)", d.RouteSink, "gdpr_sink")
+	}
+}
+
+func TestRuleEngineEvaluate_ASNMatch(t *testing.T) {
+	engine := NewRuleEngine([]Rule{
+		{Matcher: MatcherIPASN, Value: "64512", Action: ActionDeny},
+	})
+
+	if d := engine.Evaluate(Info{ASN: 64512}); d.Allowed {
+		t.Error("expected ASN match to deny")
+	}
+	if d := engine.Evaluate(Info{ASN: 64513}); !d.Allowed {
+		t.Error("expected non-matching ASN to fall through to default allow")
+	}
+}
+
+func TestRuleEngineEvaluate_NoMatchDefaultsAllow(t *testing.T) {
+	engine := NewRuleEngine([]Rule{
+		{Matcher: MatcherIPCIDR, Value: "10.0.0.0/8", Action: ActionDeny},
+	})
+
+	d := engine.Evaluate(Info{IP: "203.0.113.5"})
+	if !d.Allowed {
+		t.Error("expected no matching rule to default to allow")
+	}
+}
+
+func TestLoadRuleEngineYAML(t *testing.T) {
+	yamlDoc := []byte(`
+- matcher: IP-CIDR
+  value: 10.0.0.0/8
+  action: tag
+  action_value: internal
+- matcher: UA-BOT
+  action: deny
+`)
+	engine, err := LoadRuleEngineYAML(yamlDoc)
+	if err != nil {
+		t.Fatalf("LoadRuleEngineYAML: %v", err)
+	}
+
+	d := engine.Evaluate(Info{IsBot: true})
+	if d.Allowed {
+		t.Error("expected bot rule loaded from YAML to deny")
+	}
+}
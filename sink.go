@@ -0,0 +1,174 @@
+package tracking
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultAsyncFlushInterval is used by NewAsyncSink when flushInterval <= 0.
+const defaultAsyncFlushInterval = 5 * time.Second
+
+// Sink persists a LoginTracking record somewhere: Postgres, a message queue,
+// a test double, etc. An implementation may keep lt after Record returns
+// (AsyncSink queues it for a later flush).
+type Sink interface {
+	Record(ctx context.Context, lt *LoginTracking) error
+}
+
+// BatchSink is implemented by sinks that can persist several records in one
+// round trip. AsyncSink uses it when available instead of looping Record.
+type BatchSink interface {
+	RecordBatch(ctx context.Context, lts []*LoginTracking) error
+}
+
+// DBSink writes to the existing public.logintracking table via Insert/InsertBatch.
+type DBSink struct{}
+
+// NewDBSink returns a Sink backed by the current Postgres Insert() path.
+func NewDBSink() *DBSink {
+	return &DBSink{}
+}
+
+func (DBSink) Record(_ context.Context, lt *LoginTracking) error {
+	return lt.Insert()
+}
+
+func (DBSink) RecordBatch(_ context.Context, lts []*LoginTracking) error {
+	return InsertBatch(lts)
+}
+
+// MultiSink fans a single Record out to every wrapped Sink, returning the
+// first error encountered (after attempting all of them).
+type MultiSink struct {
+	sinks []Sink
+}
+
+// NewMultiSink wraps one or more sinks so a single event reaches all of them.
+func NewMultiSink(sinks ...Sink) *MultiSink {
+	return &MultiSink{sinks: sinks}
+}
+
+func (m *MultiSink) Record(ctx context.Context, lt *LoginTracking) error {
+	var firstErr error
+	for _, s := range m.sinks {
+		if err := s.Record(ctx, lt); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// NoopSink discards every record; useful in tests and for disabling tracking
+// without changing call sites.
+type NoopSink struct{}
+
+func (NoopSink) Record(context.Context, *LoginTracking) error { return nil }
+
+// AsyncSink buffers records in memory and flushes them to an underlying Sink
+// in batches, either when batchSize records have queued up or flushInterval
+// has elapsed, whichever comes first. This keeps request handling from
+// blocking on a database round trip.
+type AsyncSink struct {
+	underlying    Sink
+	batchSize     int
+	flushInterval time.Duration
+
+	queue chan *LoginTracking
+	done  chan struct{}
+	wg    sync.WaitGroup
+}
+
+// NewAsyncSink starts a background flush loop that drains into underlying.
+// bufferSize bounds how many pending records AsyncSink will hold before
+// Record starts blocking the caller.
+func NewAsyncSink(underlying Sink, batchSize int, flushInterval time.Duration, bufferSize int) *AsyncSink {
+	if batchSize <= 0 {
+		batchSize = 50
+	}
+	if flushInterval <= 0 {
+		flushInterval = defaultAsyncFlushInterval
+	}
+	if bufferSize <= 0 {
+		bufferSize = batchSize * 4
+	}
+	a := &AsyncSink{
+		underlying:    underlying,
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		queue:         make(chan *LoginTracking, bufferSize),
+		done:          make(chan struct{}),
+	}
+	a.wg.Add(1)
+	go a.loop()
+	return a
+}
+
+func (a *AsyncSink) Record(ctx context.Context, lt *LoginTracking) error {
+	select {
+	case a.queue <- lt:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops the flush loop after draining whatever is still queued.
+func (a *AsyncSink) Close() error {
+	close(a.done)
+	a.wg.Wait()
+	return nil
+}
+
+func (a *AsyncSink) loop() {
+	defer a.wg.Done()
+	ticker := time.NewTicker(a.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*LoginTracking, 0, a.batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		a.flush(batch)
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case lt := <-a.queue:
+			batch = append(batch, lt)
+			if len(batch) >= a.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-a.done:
+			for {
+				select {
+				case lt := <-a.queue:
+					batch = append(batch, lt)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+func (a *AsyncSink) flush(batch []*LoginTracking) {
+	ctx := context.Background()
+	if bs, ok := a.underlying.(BatchSink); ok {
+		if err := bs.RecordBatch(ctx, batch); err != nil {
+			fmt.Println("⚠️ async sink batch flush failed:", err)
+		}
+		return
+	}
+	for _, lt := range batch {
+		if err := a.underlying.Record(ctx, lt); err != nil {
+			fmt.Println("⚠️ async sink flush failed:", err)
+		}
+	}
+}
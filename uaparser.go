@@ -0,0 +1,93 @@
+package tracking
+
+import (
+	"strings"
+
+	"github.com/mssola/user_agent"
+)
+
+// UAInfo is the structured result of parsing a User-Agent string.
+type UAInfo struct {
+	Browser        string
+	BrowserVersion string
+	Platform       string // OS name, e.g. "Windows", "macOS"
+	OSVersion      string
+	DeviceType     string // "desktop" | "mobile" | "tablet" | "bot"
+	DeviceModel    string
+	IsBot          bool
+}
+
+// UAParser turns a raw User-Agent header into structured fields. It is an
+// interface so the parsing library behind it stays swappable.
+type UAParser interface {
+	Parse(ua string) UAInfo
+}
+
+// defaultUAParser is used by Collector.Extract. Code in this package can
+// replace it before serving traffic to swap in a different UAParser; it is
+// unexported, so code outside the package cannot.
+var defaultUAParser UAParser = mssolaUAParser{}
+
+// botSignatures are checked before any browser/OS detection runs, since a
+// crawler's UA can otherwise look enough like a real browser's to misclassify it.
+var botSignatures = []string{
+	"bot", "crawl", "spider", "slurp", "googlebot", "bingbot", "yandexbot",
+	"duckduckbot", "baiduspider", "facebookexternalhit", "curl", "wget",
+	"python-requests", "go-http-client", "okhttp", "postmanruntime",
+}
+
+// mssolaUAParser implements UAParser on top of github.com/mssola/user_agent.
+type mssolaUAParser struct{}
+
+func (mssolaUAParser) Parse(ua string) UAInfo {
+	if isBotUA(ua) {
+		return UAInfo{Browser: "Unknown", Platform: "Unknown", DeviceType: "bot", IsBot: true}
+	}
+
+	parsed := user_agent.New(ua)
+	name, version := parsed.Browser()
+
+	info := UAInfo{
+		Browser:        name,
+		BrowserVersion: version,
+		Platform:       parsed.OSInfo().Name,
+		OSVersion:      parsed.OSInfo().Version,
+		DeviceModel:    parsed.Model(),
+	}
+
+	switch {
+	case parsed.Bot():
+		info.DeviceType = "bot"
+		info.IsBot = true
+	case parsed.Mobile() && isTabletUA(ua):
+		info.DeviceType = "tablet"
+	case parsed.Mobile():
+		info.DeviceType = "mobile"
+	default:
+		info.DeviceType = "desktop"
+	}
+
+	if info.Browser == "" {
+		info.Browser = "Unknown"
+	}
+	if info.Platform == "" {
+		info.Platform = "Unknown"
+	}
+	return info
+}
+
+func isBotUA(ua string) bool {
+	u := strings.ToLower(ua)
+	for _, sig := range botSignatures {
+		if strings.Contains(u, sig) {
+			return true
+		}
+	}
+	return false
+}
+
+func isTabletUA(ua string) bool {
+	u := strings.ToLower(ua)
+	return strings.Contains(u, "ipad") || strings.Contains(u, "tablet") ||
+		(strings.Contains(u, "android") && !strings.Contains(u, "mobile"))
+}